@@ -0,0 +1,123 @@
+// Package alert fans a replication-manager state transition out to
+// every configured notification backend (SMTP, Slack/webhook,
+// PagerDuty, syslog), instead of the previous hardcoded
+// alert.Alert{...}.Email() call gated on mailTo != "".
+package alert
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Event describes a single server state transition. It carries enough
+// structured context for a backend to render a useful message without
+// reaching back into ServerMonitor.
+type Event struct {
+	ClusterName  string
+	ServerURL    string
+	PrevState    string
+	NewState     string
+	GTIDPosition string
+	Candidate    string // elected candidate, when the sender sets one; the monitor's per-server transition alerts leave it empty
+	At           time.Time
+}
+
+// dedupKey identifies events that should be collapsed together within
+// a backend's dedup window: the same server flapping between the same
+// two states repeatedly is one incident, not N.
+func (e Event) dedupKey() string {
+	return e.ClusterName + "|" + e.ServerURL + "|" + e.PrevState + "|" + e.NewState
+}
+
+// String renders a human-readable one-liner, used by backends that
+// just need free text (SMTP body, Slack message, syslog line).
+func (e Event) String() string {
+	if e.Candidate != "" {
+		return fmt.Sprintf("[%s] %s: %s -> %s (candidate: %s, GTID: %s)",
+			e.ClusterName, e.ServerURL, e.PrevState, e.NewState, e.Candidate, e.GTIDPosition)
+	}
+	return fmt.Sprintf("[%s] %s: %s -> %s (GTID: %s)",
+		e.ClusterName, e.ServerURL, e.PrevState, e.NewState, e.GTIDPosition)
+}
+
+// Notifier is implemented by every alerting backend. Name is used in
+// logs and in the output of `notify test` so an operator can tell
+// which configured sink failed.
+type Notifier interface {
+	Name() string
+	Notify(Event) error
+}
+
+// Dedup wraps an ordered list of Notifier backends and suppresses
+// repeat delivery of the same transition within window, so a flapping
+// server does not page someone once per check tick.
+type Dedup struct {
+	backends []Notifier
+	window   time.Duration
+
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+// NewDedup builds a fan-out Notifier over backends, collapsing
+// repeats of the same (cluster, server, prev, new) transition seen
+// again within window. A zero window disables deduplication.
+func NewDedup(window time.Duration, backends ...Notifier) *Dedup {
+	return &Dedup{backends: backends, window: window, last: map[string]time.Time{}}
+}
+
+// Notify fans e out to every backend, collecting (not stopping on) the
+// first error from each so one misconfigured backend doesn't prevent
+// the others from firing.
+func (d *Dedup) Notify(e Event) error {
+	if d.window > 0 {
+		d.mu.Lock()
+		key := e.dedupKey()
+		if last, ok := d.last[key]; ok && e.At.Sub(last) < d.window {
+			d.mu.Unlock()
+			return nil
+		}
+		d.last[key] = e.At
+		d.mu.Unlock()
+	}
+
+	var errs []error
+	for _, b := range d.backends {
+		if err := b.Notify(e); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", b.Name(), err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("alert: %d of %d backends failed: %v", len(errs), len(d.backends), errs)
+	}
+	return nil
+}
+
+// Backends exposes the wrapped notifiers, used by `notify test` to
+// exercise each one individually and report per-backend success.
+func (d *Dedup) Backends() []Notifier {
+	return d.backends
+}
+
+// RunTest sends a synthetic transition through every backend
+// individually (bypassing dedup) and returns a map of backend name to
+// the error it returned, if any. It backs the `notify test` CLI
+// subcommand, letting an operator validate every configured sink
+// without waiting for a real failure.
+func RunTest(backends []Notifier) map[string]error {
+	event := Event{
+		ClusterName:  "test",
+		ServerURL:    "127.0.0.1:3306",
+		PrevState:    "Slave",
+		NewState:     "Master",
+		GTIDPosition: "0-1-0",
+		Candidate:    "127.0.0.1:3306",
+		At:           time.Now(),
+	}
+	results := make(map[string]error, len(backends))
+	for _, b := range backends {
+		results[b.Name()] = b.Notify(event)
+	}
+	return results
+}
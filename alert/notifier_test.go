@@ -0,0 +1,72 @@
+package alert
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// recordingNotifier counts how many times Notify is called, optionally
+// returning a fixed error so dedup-suppressed calls can be told apart
+// from ones that actually reached the backend.
+type recordingNotifier struct {
+	name  string
+	err   error
+	calls int
+}
+
+func (r *recordingNotifier) Name() string { return r.name }
+func (r *recordingNotifier) Notify(Event) error {
+	r.calls++
+	return r.err
+}
+
+func TestDedupSuppressesRepeatWithinWindow(t *testing.T) {
+	backend := &recordingNotifier{name: "fake"}
+	d := NewDedup(time.Minute, backend)
+
+	base := time.Unix(0, 0)
+	e1 := Event{ClusterName: "c1", ServerURL: "s1", PrevState: "Master", NewState: "Failed", At: base}
+	e2 := e1
+	e2.At = base.Add(30 * time.Second)
+
+	if err := d.Notify(e1); err != nil {
+		t.Fatalf("first Notify: %v", err)
+	}
+	if err := d.Notify(e2); err != nil {
+		t.Fatalf("second Notify: %v", err)
+	}
+	if backend.calls != 1 {
+		t.Fatalf("backend.calls = %d, want 1 (second event within window should be suppressed)", backend.calls)
+	}
+}
+
+func TestDedupAllowsAfterWindowElapses(t *testing.T) {
+	backend := &recordingNotifier{name: "fake"}
+	d := NewDedup(time.Minute, backend)
+
+	base := time.Unix(0, 0)
+	e1 := Event{ClusterName: "c1", ServerURL: "s1", PrevState: "Master", NewState: "Failed", At: base}
+	e2 := e1
+	e2.At = base.Add(2 * time.Minute)
+
+	d.Notify(e1)
+	d.Notify(e2)
+	if backend.calls != 2 {
+		t.Fatalf("backend.calls = %d, want 2 (event outside window should not be suppressed)", backend.calls)
+	}
+}
+
+func TestDedupFansOutToEveryBackendAndCollectsErrors(t *testing.T) {
+	ok := &recordingNotifier{name: "ok"}
+	failing := &recordingNotifier{name: "failing", err: errors.New("boom")}
+	d := NewDedup(0, ok, failing)
+
+	err := d.Notify(Event{ClusterName: "c1", ServerURL: "s1"})
+	if err == nil {
+		t.Fatal("Notify() error = nil, want error reporting the failing backend")
+	}
+	if ok.calls != 1 || failing.calls != 1 {
+		t.Fatalf("ok.calls=%d failing.calls=%d, want both backends invoked once", ok.calls, failing.calls)
+	}
+}
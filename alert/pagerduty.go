@@ -0,0 +1,61 @@
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// pagerDutyEventsURL is the PagerDuty Events API v2 endpoint.
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDutyNotifier triggers a PagerDuty Events API v2 incident for
+// each state transition. Failures back to a healthy state ("Failed" ->
+// "Slave"/"Master") are sent as a resolve rather than a trigger so
+// on-call isn't paged for a recovery.
+type PagerDutyNotifier struct {
+	RoutingKey string
+}
+
+func (p PagerDutyNotifier) Name() string { return "pagerduty" }
+
+func (p PagerDutyNotifier) Notify(e Event) error {
+	action := "trigger"
+	if e.PrevState == "Failed" {
+		action = "resolve"
+	}
+	body := struct {
+		RoutingKey  string `json:"routing_key"`
+		EventAction string `json:"event_action"`
+		DedupKey    string `json:"dedup_key"`
+		Payload     struct {
+			Summary   string `json:"summary"`
+			Source    string `json:"source"`
+			Severity  string `json:"severity"`
+			Timestamp string `json:"timestamp"`
+		} `json:"payload"`
+	}{
+		RoutingKey:  p.RoutingKey,
+		EventAction: action,
+		DedupKey:    e.dedupKey(),
+	}
+	body.Payload.Summary = e.String()
+	body.Payload.Source = e.ServerURL
+	body.Payload.Severity = "critical"
+	body.Payload.Timestamp = e.At.UTC().Format("2006-01-02T15:04:05Z")
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	resp, err := http.Post(pagerDutyEventsURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("pagerduty events API returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// SlackNotifier posts a state transition to an incoming webhook URL
+// (Slack's format, also accepted by most other chat-ops webhook
+// receivers, hence "Slack/webhook" in the request).
+type SlackNotifier struct {
+	WebhookURL string
+}
+
+func (s SlackNotifier) Name() string { return "slack" }
+
+func (s SlackNotifier) Notify(e Event) error {
+	payload, err := json.Marshal(struct {
+		Text string `json:"text"`
+	}{Text: e.String()})
+	if err != nil {
+		return err
+	}
+	resp, err := http.Post(s.WebhookURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("slack webhook returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
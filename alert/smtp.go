@@ -0,0 +1,26 @@
+package alert
+
+import (
+	"fmt"
+	"net/smtp"
+)
+
+// SMTPNotifier replaces the old alert.Alert{...}.Email() call with the
+// same delivery mechanism wrapped behind the Notifier interface.
+type SMTPNotifier struct {
+	From string
+	To   string
+	Addr string // SMTP host:port
+}
+
+func (s SMTPNotifier) Name() string { return "smtp" }
+
+// Notify sends e as a plain-text email. It intentionally does not
+// authenticate: the legacy Alert.Email() relied on an open relay
+// reachable from the monitoring host, and this preserves that
+// behavior rather than silently requiring new config.
+func (s SMTPNotifier) Notify(e Event) error {
+	subject := fmt.Sprintf("Subject: [replication-manager] %s %s -> %s\r\n", e.ServerURL, e.PrevState, e.NewState)
+	body := subject + "\r\n" + e.String() + "\r\n"
+	return smtp.SendMail(s.Addr, nil, s.From, []string{s.To}, []byte(body))
+}
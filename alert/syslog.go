@@ -0,0 +1,23 @@
+// +build !windows
+
+package alert
+
+import "log/syslog"
+
+// SyslogNotifier writes each transition to the local syslog daemon at
+// warning severity, for sites that already centralize logs via
+// syslog/rsyslog rather than email or chat-ops.
+type SyslogNotifier struct {
+	Tag string
+}
+
+func (s SyslogNotifier) Name() string { return "syslog" }
+
+func (s SyslogNotifier) Notify(e Event) error {
+	w, err := syslog.New(syslog.LOG_WARNING|syslog.LOG_DAEMON, s.Tag)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	return w.Warning(e.String())
+}
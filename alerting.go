@@ -0,0 +1,87 @@
+// alerting.go
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mariadb-corporation/replication-manager/alert"
+)
+
+// alertDedupWindow bounds how often the same (cluster, server, prev,
+// new) transition re-fires a notification; slackWebhookURL,
+// pagerdutyRoutingKey and syslogTag configure the backends that are
+// optional on top of the always-available SMTP one.
+var (
+	slackWebhookURL     string
+	pagerdutyRoutingKey string
+	syslogTag           string
+	alertDedupWindow    = 5 * time.Minute
+)
+
+var (
+	notifierOnce sync.Once
+	notifiers    *alert.Dedup
+)
+
+// buildNotifiers assembles the ordered list of backends from whatever
+// subset of config is set: mailTo alone still works exactly as
+// before, and any combination of Slack/PagerDuty/syslog can be layered
+// on top without code changes.
+func buildNotifiers() []alert.Notifier {
+	var backends []alert.Notifier
+	if mailTo != "" {
+		backends = append(backends, alert.SMTPNotifier{From: mailFrom, To: mailTo, Addr: mailSMTPAddr})
+	}
+	if slackWebhookURL != "" {
+		backends = append(backends, alert.SlackNotifier{WebhookURL: slackWebhookURL})
+	}
+	if pagerdutyRoutingKey != "" {
+		backends = append(backends, alert.PagerDutyNotifier{RoutingKey: pagerdutyRoutingKey})
+	}
+	if syslogTag != "" {
+		backends = append(backends, alert.SyslogNotifier{Tag: syslogTag})
+	}
+	return backends
+}
+
+// notifierFanout lazily builds the Dedup-wrapped fan-out on first use,
+// so config flags set by the CLI parser before check() ever runs are
+// picked up without requiring an explicit Init call.
+func notifierFanout() *alert.Dedup {
+	notifierOnce.Do(func() {
+		notifiers = alert.NewDedup(alertDedupWindow, buildNotifiers()...)
+	})
+	return notifiers
+}
+
+// notifyTransition reports server's state change to every configured
+// alerting backend.
+func notifyTransition(server *ServerMonitor) {
+	event := alert.Event{
+		ClusterName:  clusterName,
+		ServerURL:    server.URL,
+		PrevState:    server.PrevState,
+		NewState:     server.State,
+		GTIDPosition: server.CurrentGtid.Sprint(),
+		At:           time.Now(),
+	}
+	if err := notifierFanout().Notify(event); err != nil {
+		logger.Errorf("Could not send alert: %s", err)
+	}
+}
+
+// cmdNotifyTest backs the `notify test` CLI subcommand: it sends a
+// synthetic transition through every configured backend individually
+// and reports which ones failed, so an operator can validate alerting
+// without waiting for a real failure.
+func cmdNotifyTest() {
+	results := alert.RunTest(notifierFanout().Backends())
+	for name, err := range results {
+		if err != nil {
+			logger.Errorf("notify test: %s failed: %s", name, err)
+			continue
+		}
+		logger.Infof("notify test: %s OK", name)
+	}
+}
@@ -0,0 +1,201 @@
+// Package api is the control surface for replication-manager: an
+// HTTP+JSON API (with an optional gRPC mirror, see grpc.go) exposing
+// topology inspection and manual operations, so operators have a
+// scriptable interface instead of signaling the daemon or editing
+// /tmp/repmgr.state by hand.
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// ServerInfo is the JSON shape returned for each server in GET
+// /topology. It carries a subset of ServerMonitor's exported fields:
+// IP, Strict, LogBin, UsingGtid, IOErrno, IOError, SQLErrno, SQLError
+// and RplMasterStatus are not included. api does not import package
+// main (that would be a cycle), so main's Backend implementation is
+// responsible for the translation.
+type ServerInfo struct {
+	URL                  string `json:"url"`
+	Host                 string `json:"host"`
+	Port                 string `json:"port"`
+	State                string `json:"state"`
+	PrevState            string `json:"prev_state"`
+	ServerID             uint   `json:"server_id"`
+	MasterServerID       uint   `json:"master_server_id"`
+	MasterHost           string `json:"master_host"`
+	ReadOnly             string `json:"read_only"`
+	IOThread             string `json:"io_thread"`
+	SQLThread            string `json:"sql_thread"`
+	DelaySeconds         int64  `json:"delay_seconds"`
+	DelayValid           bool   `json:"delay_valid"`
+	FailCount            int    `json:"fail_count"`
+	SemiSyncMasterStatus bool   `json:"semi_sync_master_status"`
+	CurrentGTID          string `json:"current_gtid"`
+	SlaveGTID            string `json:"slave_gtid"`
+	BinlogPos            string `json:"binlog_pos"`
+}
+
+// CandidateScore is one row of an election scoreboard, exposed by
+// GET /election/preview so an operator can see why a candidate would
+// or wouldn't be elected without actually promoting anything.
+type CandidateScore struct {
+	URL           string  `json:"url"`
+	Excluded      bool    `json:"excluded"`
+	ExcludeReason string  `json:"exclude_reason,omitempty"`
+	Score         float64 `json:"score"`
+}
+
+// ElectionPreview is the result of evaluating the election without
+// promoting.
+type ElectionPreview struct {
+	Winner     string           `json:"winner,omitempty"`
+	Scoreboard []CandidateScore `json:"scoreboard"`
+}
+
+// Backend is implemented by package main's adapter over ServerMonitor
+// and the cluster/election/dbconn subsystems, keeping this package
+// free of any dependency on those concrete types.
+type Backend interface {
+	Topology() []ServerInfo
+	Health(serverURL string) (status string, ok bool)
+	Failover(preferredCandidate string) error
+	Switchover() error
+	Rejoin(serverURL string) error
+	SetReadOnly(serverURL string, readOnly bool) error
+	ElectionPreview() ElectionPreview
+}
+
+// AuditEntry records one mutating call: who made it, when, what it
+// was, and the topology diff it produced, so operators have a
+// trail for every failover/switchover/rejoin/set-readonly triggered
+// through the API instead of only through direct daemon signaling.
+type AuditEntry struct {
+	At             time.Time    `json:"at"`
+	Actor          string       `json:"actor"`
+	Action         string       `json:"action"`
+	Params         interface{}  `json:"params,omitempty"`
+	BeforeTopology []ServerInfo `json:"before_topology"`
+	AfterTopology  []ServerInfo `json:"after_topology"`
+	Error          string       `json:"error,omitempty"`
+}
+
+// AuditLogger persists AuditEntry records. Implementations might
+// append to a file, a database table, or the same cluster.Store used
+// for HA state.
+type AuditLogger interface {
+	Record(AuditEntry)
+}
+
+// discardAudit is used when no AuditLogger is configured, so Server
+// never has to nil-check it.
+type discardAudit struct{}
+
+func (discardAudit) Record(AuditEntry) {}
+
+// Authenticator validates the bearer token or client certificate
+// identity on a mutating request and returns the actor name to record
+// in the audit log.
+type Authenticator interface {
+	Authenticate(r *http.Request) (actor string, ok bool)
+}
+
+// staticToken is the simplest Authenticator: a single shared bearer
+// token, checked against the Authorization header. mTLS deployments
+// should supply their own Authenticator that reads r.TLS instead.
+type staticToken struct {
+	token string
+}
+
+func (s staticToken) Authenticate(r *http.Request) (string, bool) {
+	got := r.Header.Get("Authorization")
+	if got == "Bearer "+s.token {
+		if cn := r.Header.Get("X-Actor"); cn != "" {
+			return cn, true
+		}
+		return "token", true
+	}
+	return "", false
+}
+
+// NewStaticTokenAuthenticator builds an Authenticator backed by a
+// single shared token, for deployments not yet using mTLS.
+func NewStaticTokenAuthenticator(token string) Authenticator {
+	return staticToken{token: token}
+}
+
+// Server wires a Backend, an Authenticator and an AuditLogger into an
+// http.Handler.
+type Server struct {
+	backend Backend
+	auth    Authenticator
+	audit   AuditLogger
+}
+
+// NewServer builds a Server. audit may be nil, in which case audit
+// entries are discarded rather than nil-checked on every call.
+func NewServer(backend Backend, auth Authenticator, audit AuditLogger) *Server {
+	if audit == nil {
+		audit = discardAudit{}
+	}
+	return &Server{backend: backend, auth: auth, audit: audit}
+}
+
+// Handler returns the http.Handler to mount, e.g. via http.ListenAndServe.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/topology", s.handleTopology)
+	mux.HandleFunc("/health/", s.handleHealth)
+	mux.HandleFunc("/election/preview", s.handleElectionPreview)
+	mux.HandleFunc("/failover", s.requireAuth(s.handleFailover, "failover"))
+	mux.HandleFunc("/switchover", s.requireAuth(s.handleSwitchover, "switchover"))
+	mux.HandleFunc("/server/", s.handleServerScoped)
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, struct {
+		Error string `json:"error"`
+	}{Error: err.Error()})
+}
+
+// requireAuth wraps a mutating handler with token/mTLS auth and audit
+// logging: it snapshots topology before and after the call so the
+// audit entry carries a real diff rather than just "something
+// changed".
+func (s *Server) requireAuth(next func(actor string, w http.ResponseWriter, r *http.Request), action string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, errMethodNotAllowed)
+			return
+		}
+		actor, ok := s.auth.Authenticate(r)
+		if !ok {
+			writeError(w, http.StatusUnauthorized, errUnauthorized)
+			return
+		}
+		before := s.backend.Topology()
+		rec := AuditEntry{At: time.Now(), Actor: actor, Action: action, BeforeTopology: before}
+		defer func() {
+			rec.AfterTopology = s.backend.Topology()
+			s.audit.Record(rec)
+		}()
+		next(actor, w, r)
+	}
+}
+
+func (s *Server) handleTopology(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.backend.Topology())
+}
+
+func (s *Server) handleElectionPreview(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.backend.ElectionPreview())
+}
@@ -0,0 +1,155 @@
+// +build grpc
+
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/mariadb-corporation/replication-manager/api/pb"
+)
+
+// GRPCServer is the optional gRPC mirror of Server, implementing the
+// ControlService defined in replication_manager.proto over the same
+// Backend/Authenticator/AuditLogger. Generate pb with:
+//
+//	protoc --go_out=. --go-grpc_out=. replication_manager.proto
+type GRPCServer struct {
+	pb.UnimplementedControlServiceServer
+	backend Backend
+	auth    Authenticator
+	audit   AuditLogger
+}
+
+// NewGRPCServer builds a GRPCServer over the same backend used by the
+// HTTP API, and registers it on grpcServer.
+func NewGRPCServer(grpcServer *grpc.Server, backend Backend, auth Authenticator, audit AuditLogger) *GRPCServer {
+	if audit == nil {
+		audit = discardAudit{}
+	}
+	s := &GRPCServer{backend: backend, auth: auth, audit: audit}
+	pb.RegisterControlServiceServer(grpcServer, s)
+	return s
+}
+
+func toPBServerInfo(s ServerInfo) *pb.ServerInfo {
+	return &pb.ServerInfo{
+		Url:                  s.URL,
+		Host:                 s.Host,
+		Port:                 s.Port,
+		State:                s.State,
+		PrevState:            s.PrevState,
+		ServerId:             uint32(s.ServerID),
+		MasterServerId:       uint32(s.MasterServerID),
+		MasterHost:           s.MasterHost,
+		ReadOnly:             s.ReadOnly,
+		IoThread:             s.IOThread,
+		SqlThread:            s.SQLThread,
+		DelaySeconds:         s.DelaySeconds,
+		DelayValid:           s.DelayValid,
+		FailCount:            int32(s.FailCount),
+		SemiSyncMasterStatus: s.SemiSyncMasterStatus,
+		CurrentGtid:          s.CurrentGTID,
+		SlaveGtid:            s.SlaveGTID,
+		BinlogPos:            s.BinlogPos,
+	}
+}
+
+func (g *GRPCServer) GetTopology(ctx context.Context, req *pb.TopologyRequest) (*pb.TopologyResponse, error) {
+	topo := g.backend.Topology()
+	resp := &pb.TopologyResponse{Servers: make([]*pb.ServerInfo, 0, len(topo))}
+	for _, s := range topo {
+		resp.Servers = append(resp.Servers, toPBServerInfo(s))
+	}
+	return resp, nil
+}
+
+func (g *GRPCServer) GetHealth(ctx context.Context, req *pb.HealthRequest) (*pb.HealthResponse, error) {
+	status, _ := g.backend.Health(req.ServerUrl)
+	return &pb.HealthResponse{Status: status}, nil
+}
+
+// authenticate adapts Authenticator's http.Request-based interface to
+// gRPC metadata: incoming metadata pairs become header values on a
+// bare http.Request so a header-based Authenticator (the same
+// "authorization" / "x-actor" conventions) works for both transports.
+// The request carries no TLS state (r.TLS is nil), so an Authenticator
+// that identifies callers by client certificate rejects every gRPC call.
+func (g *GRPCServer) authenticate(ctx context.Context) (actor string, ok bool) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	header := make(http.Header, len(md))
+	for k, vs := range md {
+		for _, v := range vs {
+			header.Add(k, v)
+		}
+	}
+	return g.auth.Authenticate(&http.Request{Header: header})
+}
+
+// audited requires the same header credentials as the HTTP
+// requireAuth wrapper before running fn (see authenticate for what the
+// Authenticator is given), and records the action in the audit log the
+// same way, including the actor and timestamp.
+func (g *GRPCServer) audited(ctx context.Context, action string, fn func() error) (*pb.OpResponse, error) {
+	actor, ok := g.authenticate(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, errUnauthorized.Error())
+	}
+	before := g.backend.Topology()
+	err := fn()
+	g.audit.Record(AuditEntry{
+		At:             time.Now(),
+		Actor:          actor,
+		Action:         action,
+		BeforeTopology: before,
+		AfterTopology:  g.backend.Topology(),
+	})
+	if err != nil {
+		return &pb.OpResponse{Ok: false, Error: err.Error()}, nil
+	}
+	return &pb.OpResponse{Ok: true}, nil
+}
+
+func (g *GRPCServer) Failover(ctx context.Context, req *pb.FailoverRequest) (*pb.OpResponse, error) {
+	return g.audited(ctx, "failover", func() error {
+		return g.backend.Failover(req.PreferredCandidate)
+	})
+}
+
+func (g *GRPCServer) Switchover(ctx context.Context, req *pb.SwitchoverRequest) (*pb.OpResponse, error) {
+	return g.audited(ctx, "switchover", func() error {
+		return g.backend.Switchover()
+	})
+}
+
+func (g *GRPCServer) Rejoin(ctx context.Context, req *pb.ServerRequest) (*pb.OpResponse, error) {
+	return g.audited(ctx, "rejoin:"+req.ServerUrl, func() error {
+		return g.backend.Rejoin(req.ServerUrl)
+	})
+}
+
+func (g *GRPCServer) SetReadOnly(ctx context.Context, req *pb.SetReadOnlyRequest) (*pb.OpResponse, error) {
+	return g.audited(ctx, "set-readonly:"+req.ServerUrl, func() error {
+		return g.backend.SetReadOnly(req.ServerUrl, req.ReadOnly)
+	})
+}
+
+func (g *GRPCServer) PreviewElection(ctx context.Context, req *pb.ElectionPreviewRequest) (*pb.ElectionPreviewResponse, error) {
+	preview := g.backend.ElectionPreview()
+	resp := &pb.ElectionPreviewResponse{Winner: preview.Winner}
+	for _, cs := range preview.Scoreboard {
+		resp.Scoreboard = append(resp.Scoreboard, &pb.CandidateScore{
+			Url:           cs.URL,
+			Excluded:      cs.Excluded,
+			ExcludeReason: cs.ExcludeReason,
+			Score:         cs.Score,
+		})
+	}
+	return resp, nil
+}
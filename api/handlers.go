@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var errUnauthorized = errors.New("api: missing or invalid credentials")
+var errMethodNotAllowed = errors.New("api: method not allowed")
+
+// handleHealth serves GET /health/{server}, where {server} is the
+// server's URL (host:port), returning the same string
+// ServerMonitor.healthCheck() would.
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	serverURL := strings.TrimPrefix(r.URL.Path, "/health/")
+	if serverURL == "" {
+		writeError(w, http.StatusBadRequest, errors.New("api: missing server in path"))
+		return
+	}
+	status, ok := s.backend.Health(serverURL)
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("api: unknown server "+serverURL))
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Status string `json:"status"`
+	}{Status: status})
+}
+
+// failoverRequest is the POST /failover body. PreferredCandidate, when
+// set, overrides prefMaster for this call only, without touching the
+// persisted config.
+type failoverRequest struct {
+	PreferredCandidate string `json:"preferred_candidate,omitempty"`
+}
+
+func (s *Server) handleFailover(actor string, w http.ResponseWriter, r *http.Request) {
+	var req failoverRequest
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&req)
+	}
+	if err := s.backend.Failover(req.PreferredCandidate); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Status string `json:"status"`
+	}{Status: "ok"})
+}
+
+func (s *Server) handleSwitchover(actor string, w http.ResponseWriter, r *http.Request) {
+	if err := s.backend.Switchover(); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Status string `json:"status"`
+	}{Status: "ok"})
+}
+
+// handleServerScoped dispatches POST /server/{url}/rejoin and
+// POST /server/{url}/set-readonly, both of which need the same
+// token/mTLS + audit wrapping as the other mutating endpoints but are
+// easiest to route by trailing path segment rather than registering
+// one mux pattern per action.
+func (s *Server) handleServerScoped(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/server/")
+	idx := strings.LastIndex(path, "/")
+	if idx < 0 {
+		writeError(w, http.StatusNotFound, errors.New("api: expected /server/{url}/{action}"))
+		return
+	}
+	serverURL, action := path[:idx], path[idx+1:]
+
+	switch action {
+	case "rejoin":
+		s.requireAuth(func(actor string, w http.ResponseWriter, r *http.Request) {
+			if err := s.backend.Rejoin(serverURL); err != nil {
+				writeError(w, http.StatusConflict, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, struct {
+				Status string `json:"status"`
+			}{Status: "ok"})
+		}, "rejoin:"+serverURL)(w, r)
+	case "set-readonly":
+		var req struct {
+			ReadOnly bool `json:"read_only"`
+		}
+		if r.Body != nil {
+			json.NewDecoder(r.Body).Decode(&req)
+		}
+		s.requireAuth(func(actor string, w http.ResponseWriter, r *http.Request) {
+			if err := s.backend.SetReadOnly(serverURL, req.ReadOnly); err != nil {
+				writeError(w, http.StatusConflict, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, struct {
+				Status string `json:"status"`
+			}{Status: "ok"})
+		}, "set-readonly:"+serverURL)(w, r)
+	default:
+		writeError(w, http.StatusNotFound, errors.New("api: unknown action "+action))
+	}
+}
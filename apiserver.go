@@ -0,0 +1,153 @@
+// apiserver.go
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mariadb-corporation/replication-manager/api"
+	"github.com/tanji/mariadb-tools/dbhelper"
+)
+
+// apiToken authenticates mutating API calls; apiListenAddr is where
+// the HTTP control API listens (e.g. ":9999"). Both are set by the CLI
+// flag parser before startAPIServer runs.
+var (
+	apiToken      string
+	apiListenAddr string
+)
+
+// backendAdapter implements api.Backend over package main's globals
+// (master, slaves) and existing ServerMonitor/election methods, so the
+// api package itself never needs to import package main.
+type backendAdapter struct{}
+
+func toServerInfo(s *ServerMonitor) api.ServerInfo {
+	return api.ServerInfo{
+		URL:                  s.URL,
+		Host:                 s.Host,
+		Port:                 s.Port,
+		State:                s.State,
+		PrevState:            s.PrevState,
+		ServerID:             s.ServerID,
+		MasterServerID:       s.MasterServerID,
+		MasterHost:           s.MasterHost,
+		ReadOnly:             s.ReadOnly,
+		IOThread:             s.IOThread,
+		SQLThread:            s.SQLThread,
+		DelaySeconds:         s.Delay.Int64,
+		DelayValid:           s.Delay.Valid,
+		FailCount:            s.FailCount,
+		SemiSyncMasterStatus: s.SemiSyncMasterStatus,
+		CurrentGTID:          s.CurrentGtid.Sprint(),
+		SlaveGTID:            s.SlaveGtid.Sprint(),
+		BinlogPos:            s.BinlogPos.Sprint(),
+	}
+}
+
+func (backendAdapter) Topology() []api.ServerInfo {
+	all := append([]*ServerMonitor{master}, slaves...)
+	infos := make([]api.ServerInfo, 0, len(all))
+	for _, s := range all {
+		if s == nil {
+			continue
+		}
+		infos = append(infos, toServerInfo(s))
+	}
+	return infos
+}
+
+func (backendAdapter) Health(serverURL string) (string, bool) {
+	for _, s := range append([]*ServerMonitor{master}, slaves...) {
+		if s != nil && s.URL == serverURL {
+			return s.healthCheck(), true
+		}
+	}
+	return "", false
+}
+
+// Failover promotes the elected candidate (or preferredCandidate, if
+// given) in place of the failed master by reusing masterFailover, the
+// same routine the monitor loop's automatic failover path uses. The
+// old master is presumed dead and dropped from the topology; use
+// Switchover for a planned rotation of a still-healthy master.
+// preferredCandidate is passed through to electCandidate as a
+// parameter rather than written to the prefMaster global, so
+// concurrent API calls can't race each other's preference.
+func (backendAdapter) Failover(preferredCandidate string) error {
+	candidate := preferredCandidate
+	if candidate == "" {
+		candidate = prefMaster
+	}
+	result := master.electCandidate(slaves, candidate)
+	return masterFailover(result)
+}
+
+// Switchover is a planned failover to the preferred master: like
+// Failover, but the still-healthy current master is demoted and
+// re-slaved onto the winner (via masterSwitchover) instead of being
+// abandoned.
+func (backendAdapter) Switchover() error {
+	result := master.electCandidate(slaves, prefMaster)
+	return masterSwitchover(result)
+}
+
+func (backendAdapter) Rejoin(serverURL string) error {
+	for _, s := range slaves {
+		if s.URL == serverURL {
+			return s.rejoin()
+		}
+	}
+	return fmt.Errorf("api: unknown server %s", serverURL)
+}
+
+func (backendAdapter) SetReadOnly(serverURL string, readOnly bool) error {
+	if !isLeader() {
+		return fmt.Errorf("api: refusing to set read-only, this instance is not the cluster leader")
+	}
+	for _, s := range append([]*ServerMonitor{master}, slaves...) {
+		if s != nil && s.URL == serverURL {
+			return dbhelper.SetReadOnly(s.AdminConn, readOnly)
+		}
+	}
+	return fmt.Errorf("api: unknown server %s", serverURL)
+}
+
+func (backendAdapter) ElectionPreview() api.ElectionPreview {
+	result := master.electCandidate(slaves, prefMaster)
+	preview := api.ElectionPreview{}
+	if result.Winner != nil {
+		preview.Winner = result.Winner.URL
+	}
+	for _, cs := range result.Scoreboard {
+		if cs.Server == nil {
+			continue
+		}
+		preview.Scoreboard = append(preview.Scoreboard, api.CandidateScore{
+			URL:           cs.Server.URL,
+			Excluded:      cs.Excluded,
+			ExcludeReason: cs.ExcludeReason,
+			Score:         cs.Score,
+		})
+	}
+	return preview
+}
+
+// startAPIServer starts the HTTP control API in the background. It is
+// a no-op if apiListenAddr is unset, consistent with the other
+// optional subsystems (metrics, HA) staying off by
+// default. It refuses to start at all if apiToken is empty: without a
+// token, staticToken.Authenticate accepts any "Bearer " header, which
+// would stand up an unauthenticated failover/switchover/rejoin/
+// set-readonly API.
+func startAPIServer() error {
+	if apiListenAddr == "" {
+		return nil
+	}
+	if apiToken == "" {
+		return fmt.Errorf("api: refusing to start control API on %s without an apiToken configured", apiListenAddr)
+	}
+	srv := api.NewServer(backendAdapter{}, api.NewStaticTokenAuthenticator(apiToken), nil)
+	go http.ListenAndServe(apiListenAddr, srv.Handler())
+	return nil
+}
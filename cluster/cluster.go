@@ -0,0 +1,187 @@
+// Package cluster coordinates multiple replication-manager instances so
+// that exactly one of them is ever allowed to drive failover against a
+// given MariaDB topology at a time.
+//
+// A replication-manager process that runs `check`/`freeze`/`failover`/
+// `rejoin` alone against a shared cluster is a single point of failure:
+// if the box dies mid-outage, nothing promotes a new master. Package
+// cluster layers a consensus-backed leader election underneath the
+// monitor loop so only the elected leader performs mutating operations,
+// while every instance keeps a replicated view of topology (server
+// list, per-server State/FailCount/BinlogPos, last elected candidate)
+// so a newly elected leader can resume without rediscovering the world.
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ServerState is the subset of ServerMonitor that followers need in
+// order to pick up cleanly after a leadership change.
+type ServerState struct {
+	URL       string `json:"url"`
+	State     string `json:"state"`
+	FailCount int    `json:"fail_count"`
+	BinlogPos string `json:"binlog_pos"`
+}
+
+// Snapshot is the full replicated view of the cluster at a point in
+// time. It is what gets appended to the consensus log on every tick,
+// and what a new leader reads back before resuming checks.
+type Snapshot struct {
+	Servers         []ServerState `json:"servers"`
+	LastCandidate   string        `json:"last_candidate"`
+	LastElectionAt  time.Time     `json:"last_election_at"`
+}
+
+// Store is the minimal persistence seam a consensus backend must
+// satisfy. It replaces the old practice of writing straight to
+// /tmp/repmgr.state: Put goes through the backend's replicated log, so
+// every follower observes the same sequence of snapshots the leader
+// produced.
+type Store interface {
+	// Put replicates state under key, returning once a quorum has
+	// acknowledged it.
+	Put(ctx context.Context, key string, snap Snapshot) error
+	// Get returns the last replicated snapshot for key.
+	Get(ctx context.Context, key string) (Snapshot, error)
+}
+
+// Elector abstracts the leader-election primitive so the monitor loop
+// does not need to know whether the backend is Raft, etcd or ZooKeeper.
+// Implementations must guarantee that at most one instance observes
+// IsLeader() == true for a given election name at any time, even across
+// a network partition (the losing side must step down, not merely
+// retry).
+type Elector interface {
+	// Campaign blocks until this instance becomes leader or ctx is
+	// cancelled.
+	Campaign(ctx context.Context) error
+	// Resign gives up leadership voluntarily, e.g. on clean shutdown.
+	Resign(ctx context.Context) error
+	// IsLeader reports whether this instance currently holds the lock.
+	// It must flip to false promptly after a partition or session loss
+	// so a partitioned former leader stops driving failovers.
+	IsLeader() bool
+	// Leader returns the id of the current leader, if known.
+	Leader(ctx context.Context) (string, error)
+}
+
+// Manager wires an Elector and a Store together and exposes the single
+// question the monitor loop actually needs to ask before doing
+// anything mutating: "am I allowed to act?".
+type Manager struct {
+	Name    string // election name, e.g. the cluster name
+	ID      string // this instance's identity, e.g. host:port
+	Elector Elector
+	Store   Store
+
+	mu     sync.RWMutex
+	latest Snapshot
+}
+
+// NewManager builds a Manager for the given election name and local
+// identity. The caller is responsible for starting a background
+// Campaign via Run.
+func NewManager(name, id string, elector Elector, store Store) *Manager {
+	return &Manager{Name: name, ID: id, Elector: elector, Store: store}
+}
+
+// Run campaigns for leadership in the background until ctx is done. On
+// becoming leader it loads the last replicated snapshot so the new
+// leader resumes with the same view of topology the previous leader
+// had, avoiding a blind failover decision right after takeover. If this
+// instance later loses leadership (session lost, resigned) while ctx is
+// still live, Run re-campaigns instead of leaving it stuck as a
+// follower forever.
+func (m *Manager) Run(ctx context.Context) error {
+	for {
+		if err := m.Elector.Campaign(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("cluster: campaign for %s failed: %w", m.Name, err)
+		}
+		snap, err := m.Store.Get(ctx, m.Name)
+		if err != nil {
+			return fmt.Errorf("cluster: loading last snapshot for %s failed: %w", m.Name, err)
+		}
+		m.mu.Lock()
+		m.latest = snap
+		m.mu.Unlock()
+
+		m.waitUntilNotLeader(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// waitUntilNotLeader blocks until IsLeader() goes false or ctx is
+// done, so Run knows when to re-campaign.
+func (m *Manager) waitUntilNotLeader(ctx context.Context) {
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for m.IsLeader() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// IsLeader reports whether this instance may currently perform
+// mutating operations (check-driven failover, freeze, rejoin).
+// Followers should still refresh their local ServerMonitor state, they
+// must simply never act on it.
+func (m *Manager) IsLeader() bool {
+	return m.Elector.IsLeader()
+}
+
+// WriteState replaces ServerMonitor.writeState's direct write to
+// /tmp/repmgr.state: it replicates the snapshot through the consensus
+// store so every follower (and a future leader) converges on the same
+// state, instead of each process trusting its own local disk.
+func (m *Manager) WriteState(ctx context.Context, snap Snapshot) error {
+	if !m.IsLeader() {
+		return fmt.Errorf("cluster: refusing to write state, %s is not the leader", m.ID)
+	}
+	snap.LastElectionAt = snap.LastElectionAt.UTC()
+	if err := m.Store.Put(ctx, m.Name, snap); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.latest = snap
+	m.mu.Unlock()
+	return nil
+}
+
+// Latest returns the most recently observed replicated snapshot,
+// usable by followers that want to display topology without being
+// allowed to act on it.
+func (m *Manager) Latest() Snapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.latest
+}
+
+// MarshalSnapshot and UnmarshalSnapshot are small helpers for Store
+// implementations backed by a plain byte-oriented KV (etcd, ZooKeeper)
+// rather than something that understands Go structs natively.
+func MarshalSnapshot(snap Snapshot) ([]byte, error) {
+	return json.Marshal(snap)
+}
+
+func UnmarshalSnapshot(data []byte) (Snapshot, error) {
+	var snap Snapshot
+	if len(data) == 0 {
+		return snap, nil
+	}
+	err := json.Unmarshal(data, &snap)
+	return snap, err
+}
@@ -0,0 +1,127 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeElector is a scriptable Elector: each Campaign call flips
+// isLeader to true and, if resignAfter is set, a background goroutine
+// flips it back to false shortly after, simulating a lost session.
+type fakeElector struct {
+	mu          sync.Mutex
+	isLeader    bool
+	campaigns   int
+	resignAfter time.Duration
+}
+
+func (f *fakeElector) Campaign(ctx context.Context) error {
+	f.mu.Lock()
+	f.campaigns++
+	f.isLeader = true
+	resignAfter := f.resignAfter
+	f.mu.Unlock()
+
+	if resignAfter > 0 {
+		go func() {
+			time.Sleep(resignAfter)
+			f.mu.Lock()
+			f.isLeader = false
+			f.mu.Unlock()
+		}()
+	}
+	return nil
+}
+
+func (f *fakeElector) Resign(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.isLeader = false
+	return nil
+}
+
+func (f *fakeElector) IsLeader() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.isLeader
+}
+
+func (f *fakeElector) Leader(ctx context.Context) (string, error) {
+	if f.IsLeader() {
+		return "self", nil
+	}
+	return "", fmt.Errorf("no leader")
+}
+
+func (f *fakeElector) campaignCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.campaigns
+}
+
+type fakeStore struct {
+	mu   sync.Mutex
+	data map[string]Snapshot
+}
+
+func newFakeStore() *fakeStore { return &fakeStore{data: map[string]Snapshot{}} }
+
+func (s *fakeStore) Put(ctx context.Context, key string, snap Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = snap
+	return nil
+}
+
+func (s *fakeStore) Get(ctx context.Context, key string) (Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data[key], nil
+}
+
+func TestWriteStateRefusesWhenNotLeader(t *testing.T) {
+	m := NewManager("test", "self", &fakeElector{}, newFakeStore())
+
+	err := m.WriteState(context.Background(), Snapshot{LastCandidate: "s1"})
+	if err == nil {
+		t.Fatal("WriteState() error = nil, want refusal when not leader")
+	}
+}
+
+func TestWriteStateSucceedsWhenLeader(t *testing.T) {
+	elector := &fakeElector{isLeader: true}
+	m := NewManager("test", "self", elector, newFakeStore())
+
+	snap := Snapshot{LastCandidate: "s1"}
+	if err := m.WriteState(context.Background(), snap); err != nil {
+		t.Fatalf("WriteState() error = %v, want nil", err)
+	}
+	if got := m.Latest().LastCandidate; got != "s1" {
+		t.Fatalf("Latest().LastCandidate = %q, want %q", got, "s1")
+	}
+}
+
+func TestRunReCampaignsAfterLosingLeadership(t *testing.T) {
+	elector := &fakeElector{resignAfter: 20 * time.Millisecond}
+	store := newFakeStore()
+	store.Put(context.Background(), "test", Snapshot{LastCandidate: "seed"})
+	m := NewManager("test", "self", elector, store)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- m.Run(ctx) }()
+
+	<-ctx.Done()
+	if err := <-done; err != nil {
+		t.Fatalf("Run() error = %v, want nil on ctx done", err)
+	}
+
+	if got := elector.campaignCount(); got < 2 {
+		t.Fatalf("campaignCount() = %d, want at least 2 (should re-campaign after losing leadership)", got)
+	}
+}
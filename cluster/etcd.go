@@ -0,0 +1,130 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/clientv3/concurrency"
+)
+
+// EtcdElector implements Elector on top of etcd's concurrency package,
+// which already handles the hard part (session keepalive, revocation
+// on partition) via a lease tied to the client's session.
+type EtcdElector struct {
+	client   *clientv3.Client
+	session  *concurrency.Session
+	election *concurrency.Election
+	id       string
+
+	mu       sync.RWMutex
+	isLeader bool
+}
+
+func (e *EtcdElector) setLeader(v bool) {
+	e.mu.Lock()
+	e.isLeader = v
+	e.mu.Unlock()
+}
+
+// NewEtcdElector creates an elector that campaigns under electionPath
+// using a dedicated session. ttl is the lease TTL in seconds: if this
+// instance is partitioned away from the etcd quorum for longer than
+// ttl, its session expires and IsLeader drops to false on its own,
+// which is what prevents a partitioned former leader from continuing
+// to drive failovers.
+func NewEtcdElector(client *clientv3.Client, electionPath, id string, ttl int) (*EtcdElector, error) {
+	session, err := concurrency.NewSession(client, concurrency.WithTTL(ttl))
+	if err != nil {
+		return nil, fmt.Errorf("cluster: creating etcd session: %w", err)
+	}
+	return &EtcdElector{
+		client:   client,
+		session:  session,
+		election: concurrency.NewElection(session, electionPath),
+		id:       id,
+	}, nil
+}
+
+// Campaign blocks until this instance wins the election or ctx is done.
+func (e *EtcdElector) Campaign(ctx context.Context) error {
+	if err := e.election.Campaign(ctx, e.id); err != nil {
+		return err
+	}
+	e.setLeader(true)
+	go e.watchSession(ctx)
+	return nil
+}
+
+// watchSession clears isLeader as soon as the underlying session is
+// closed (lease expiry on partition, or explicit Resign), so callers
+// polling IsLeader see the step-down without needing their own watch.
+func (e *EtcdElector) watchSession(ctx context.Context) {
+	select {
+	case <-e.session.Done():
+		e.setLeader(false)
+	case <-ctx.Done():
+		e.setLeader(false)
+	}
+}
+
+// Resign releases leadership voluntarily.
+func (e *EtcdElector) Resign(ctx context.Context) error {
+	defer e.setLeader(false)
+	return e.election.Resign(ctx)
+}
+
+// IsLeader reports whether this instance currently holds the election.
+func (e *EtcdElector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.isLeader
+}
+
+// Leader returns the id of the current leader, if any has been
+// elected yet.
+func (e *EtcdElector) Leader(ctx context.Context) (string, error) {
+	resp, err := e.election.Leader(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Kvs) == 0 {
+		return "", fmt.Errorf("cluster: no leader elected")
+	}
+	return string(resp.Kvs[0].Value), nil
+}
+
+// EtcdStore implements Store on top of a plain etcd KV put/get,
+// keeping the replicated snapshot as JSON under a single key per
+// cluster name.
+type EtcdStore struct {
+	client *clientv3.Client
+	prefix string
+}
+
+// NewEtcdStore builds a Store that namespaces snapshot keys under
+// prefix (e.g. "/replication-manager/state/").
+func NewEtcdStore(client *clientv3.Client, prefix string) *EtcdStore {
+	return &EtcdStore{client: client, prefix: prefix}
+}
+
+func (s *EtcdStore) Put(ctx context.Context, key string, snap Snapshot) error {
+	data, err := MarshalSnapshot(snap)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.Put(ctx, s.prefix+key, string(data))
+	return err
+}
+
+func (s *EtcdStore) Get(ctx context.Context, key string) (Snapshot, error) {
+	resp, err := s.client.Get(ctx, s.prefix+key)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if len(resp.Kvs) == 0 {
+		return Snapshot{}, nil
+	}
+	return UnmarshalSnapshot(resp.Kvs[0].Value)
+}
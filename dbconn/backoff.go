@@ -0,0 +1,57 @@
+package dbconn
+
+import (
+	"sync"
+	"time"
+)
+
+// Backoff is a simple doubling backoff with a ceiling, tracking one
+// server's consecutive failure count so the check goroutine can space
+// out reconnection attempts instead of hammering (and blocking on) a
+// dead host's TCP timeout on every tick.
+type Backoff struct {
+	base time.Duration
+	max  time.Duration
+
+	mu       sync.Mutex
+	failures int
+}
+
+// NewBackoff builds a Backoff that starts at base and doubles per
+// consecutive failure, capped at max.
+func NewBackoff(base, max time.Duration) *Backoff {
+	return &Backoff{base: base, max: max}
+}
+
+// Failure records a failed attempt, growing the next Delay().
+func (b *Backoff) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+}
+
+// Success resets the backoff after a successful attempt.
+func (b *Backoff) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+}
+
+// Delay returns how long to wait before the next attempt, given the
+// current consecutive failure count.
+func (b *Backoff) Delay() time.Duration {
+	b.mu.Lock()
+	n := b.failures
+	b.mu.Unlock()
+	if n == 0 {
+		return 0
+	}
+	d := b.base
+	for i := 1; i < n; i++ {
+		d *= 2
+		if d >= b.max {
+			return b.max
+		}
+	}
+	return d
+}
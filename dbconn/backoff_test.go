@@ -0,0 +1,50 @@
+package dbconn
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffDelayDoublesAndCaps(t *testing.T) {
+	b := NewBackoff(100*time.Millisecond, 1*time.Second)
+
+	if got := b.Delay(); got != 0 {
+		t.Fatalf("Delay() with no failures = %v, want 0", got)
+	}
+
+	b.Failure()
+	if got := b.Delay(); got != 100*time.Millisecond {
+		t.Fatalf("Delay() after 1 failure = %v, want 100ms", got)
+	}
+
+	b.Failure()
+	if got := b.Delay(); got != 200*time.Millisecond {
+		t.Fatalf("Delay() after 2 failures = %v, want 200ms", got)
+	}
+
+	b.Failure()
+	if got := b.Delay(); got != 400*time.Millisecond {
+		t.Fatalf("Delay() after 3 failures = %v, want 400ms", got)
+	}
+
+	for i := 0; i < 10; i++ {
+		b.Failure()
+	}
+	if got := b.Delay(); got != 1*time.Second {
+		t.Fatalf("Delay() after many failures = %v, want capped at 1s", got)
+	}
+}
+
+func TestBackoffSuccessResets(t *testing.T) {
+	b := NewBackoff(100*time.Millisecond, 1*time.Second)
+	b.Failure()
+	b.Failure()
+	if got := b.Delay(); got == 0 {
+		t.Fatalf("Delay() after failures = 0, want nonzero")
+	}
+
+	b.Success()
+	if got := b.Delay(); got != 0 {
+		t.Fatalf("Delay() after Success() = %v, want 0", got)
+	}
+}
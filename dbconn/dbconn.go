@@ -0,0 +1,215 @@
+// Package dbconn builds the *sqlx.DB connections ServerMonitor uses,
+// replacing the single `?timeout=Ns` DSN opened once in
+// newServerMonitor. It separates the monitoring connection (cheap,
+// frequent pings from the check goroutine) from the admin connection
+// (freeze/rejoin/SetReadOnly), configures pool limits and TLS, and
+// applies exponential backoff between reconnection attempts so a dead
+// host can't stall the check loop on repeated TCP timeouts.
+package dbconn
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+)
+
+// TLSMode mirrors the DSN tls= values accepted by go-sql-driver/mysql.
+type TLSMode string
+
+const (
+	TLSDisabled   TLSMode = ""
+	TLSSkipVerify TLSMode = "skip-verify"
+	TLSPreferred  TLSMode = "preferred"
+	TLSRequired   TLSMode = "required"
+	TLSCustom     TLSMode = "custom" // registered under Config.TLSConfigName via RegisterTLS
+)
+
+// RegisterTLS registers a custom CA/client-cert TLS config under name
+// so a Config with TLSMode: TLSCustom and TLSConfigName: name picks it
+// up. Callers build tlsConfig themselves (loading CA/cert/key from
+// disk), since the specifics are deployment-dependent.
+func RegisterTLS(name string, tlsConfig *tls.Config) error {
+	return mysql.RegisterTLSConfig(name, tlsConfig)
+}
+
+// Config describes how to reach one server and how to shape its
+// connection pools. Both the monitoring and admin pool are built from
+// the same Config so they agree on host/credentials/TLS, but get their
+// own independent *sql.DB (hence independent pool limits).
+type Config struct {
+	Host string
+	Port string
+	Sock string // unix socket path, used when Host == ""
+	User string
+	Pass string
+
+	ConnectTimeout time.Duration
+
+	TLSMode       TLSMode
+	TLSConfigName string // name RegisterTLS was called with, when TLSMode == TLSCustom
+
+	// Monitoring pool: small and short-lived, tuned for frequent
+	// Ping()/refresh() calls.
+	MonitorMaxOpenConns int
+	MonitorMaxIdleConns int
+	MonitorConnMaxLife  time.Duration
+
+	// Admin pool: used by freeze/rejoin/SetReadOnly, kept separate so
+	// a saturated admin path (long DDL, KillThreads) can't starve the
+	// monitoring pool's health checks.
+	AdminMaxOpenConns int
+	AdminMaxIdleConns int
+	AdminConnMaxLife  time.Duration
+
+	// Backoff applied between reconnection attempts against this
+	// server after a failed Ping/Open.
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+}
+
+// withDefaults fills in zero-valued fields with conservative defaults
+// so a Config built from only the required host/user/pass still works.
+func (c Config) withDefaults() Config {
+	if c.ConnectTimeout == 0 {
+		c.ConnectTimeout = 5 * time.Second
+	}
+	if c.MonitorMaxOpenConns == 0 {
+		c.MonitorMaxOpenConns = 2
+	}
+	if c.MonitorMaxIdleConns == 0 {
+		c.MonitorMaxIdleConns = 2
+	}
+	if c.MonitorConnMaxLife == 0 {
+		c.MonitorConnMaxLife = 30 * time.Minute
+	}
+	if c.AdminMaxOpenConns == 0 {
+		c.AdminMaxOpenConns = 2
+	}
+	if c.AdminMaxIdleConns == 0 {
+		c.AdminMaxIdleConns = 1
+	}
+	if c.AdminConnMaxLife == 0 {
+		c.AdminConnMaxLife = 30 * time.Minute
+	}
+	if c.BackoffBase == 0 {
+		c.BackoffBase = 500 * time.Millisecond
+	}
+	if c.BackoffMax == 0 {
+		c.BackoffMax = 30 * time.Second
+	}
+	return c
+}
+
+// dsn builds the go-sql-driver/mysql DSN for cfg, including TLS
+// parameters when configured.
+func dsn(cfg Config) string {
+	params := fmt.Sprintf("?timeout=%s", cfg.ConnectTimeout)
+	if cfg.TLSMode != TLSDisabled {
+		name := string(cfg.TLSMode)
+		if cfg.TLSMode == TLSCustom {
+			name = cfg.TLSConfigName
+		}
+		params += "&tls=" + name
+	}
+	creds := cfg.User + ":" + cfg.Pass + "@"
+	if cfg.Host != "" {
+		return creds + "tcp(" + cfg.Host + ":" + cfg.Port + ")/" + params
+	}
+	return creds + "unix(" + cfg.Sock + ")/" + params
+}
+
+// Pinger is the minimal surface the check loop needs, so tests can
+// inject a fake instead of dialing real MySQL.
+type Pinger interface {
+	Ping() error
+	PingContext(ctx context.Context) error
+}
+
+// Querier is the minimal surface refresh()/electCandidate() need
+// beyond Pinger. *sqlx.DB satisfies it.
+type Querier interface {
+	Pinger
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// Pool holds the two independent connection pools for one server.
+type Pool struct {
+	cfg     Config
+	monitor *sqlx.DB
+	admin   *sqlx.DB
+	backoff *Backoff
+}
+
+// Open builds both pools for cfg. It does not fail if the server is
+// currently unreachable: sqlx.Open never dials, so a down host at
+// startup is discovered on the first Ping, same as before.
+func Open(cfg Config) (*Pool, error) {
+	cfg = cfg.withDefaults()
+	d := dsn(cfg)
+
+	monitor, err := sqlx.Open("mysql", d)
+	if err != nil {
+		return nil, fmt.Errorf("dbconn: opening monitoring pool: %w", err)
+	}
+	monitor.SetMaxOpenConns(cfg.MonitorMaxOpenConns)
+	monitor.SetMaxIdleConns(cfg.MonitorMaxIdleConns)
+	monitor.SetConnMaxLifetime(cfg.MonitorConnMaxLife)
+
+	admin, err := sqlx.Open("mysql", d)
+	if err != nil {
+		monitor.Close()
+		return nil, fmt.Errorf("dbconn: opening admin pool: %w", err)
+	}
+	admin.SetMaxOpenConns(cfg.AdminMaxOpenConns)
+	admin.SetMaxIdleConns(cfg.AdminMaxIdleConns)
+	admin.SetConnMaxLifetime(cfg.AdminConnMaxLife)
+
+	return &Pool{
+		cfg:     cfg,
+		monitor: monitor,
+		admin:   admin,
+		backoff: NewBackoff(cfg.BackoffBase, cfg.BackoffMax),
+	}, nil
+}
+
+// Monitor returns the connection used by the check/refresh loop.
+func (p *Pool) Monitor() *sqlx.DB { return p.monitor }
+
+// Admin returns the connection used by freeze/rejoin/SetReadOnly.
+func (p *Pool) Admin() *sqlx.DB { return p.admin }
+
+// Close closes both pools.
+func (p *Pool) Close() error {
+	err1 := p.monitor.Close()
+	err2 := p.admin.Close()
+	if err1 != nil {
+		return err1
+	}
+	return err2
+}
+
+// PingMonitor pings the monitoring connection, recording the result
+// against the pool's backoff so repeated failures are paced rather
+// than retried immediately.
+func (p *Pool) PingMonitor(ctx context.Context) error {
+	err := p.monitor.PingContext(ctx)
+	if err != nil {
+		p.backoff.Failure()
+		return err
+	}
+	p.backoff.Success()
+	return nil
+}
+
+// NextRetryDelay returns how long the caller should wait before
+// retrying the monitoring connection, based on the current backoff
+// state.
+func (p *Pool) NextRetryDelay() time.Duration {
+	return p.backoff.Delay()
+}
@@ -0,0 +1,384 @@
+// election.go
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mariadb-corporation/replication-manager/gtid"
+	"github.com/mariadb-corporation/replication-manager/metrics"
+	"github.com/tanji/mariadb-tools/dbhelper"
+)
+
+// DelayTier buckets Seconds_Behind_Master into a coarse band so that,
+// say, 10s and 25s of lag (both in the default 30s tier) score the same
+// instead of swinging a close election the way a raw subtraction would,
+// while 25s and 400s still score very differently.
+type DelayTier struct {
+	MaxSeconds int64
+	Score      float64
+}
+
+// defaultDelayTiers is used when the config file does not override it.
+var defaultDelayTiers = []DelayTier{
+	{MaxSeconds: 0, Score: 1.0},
+	{MaxSeconds: 5, Score: 0.9},
+	{MaxSeconds: 30, Score: 0.6},
+	{MaxSeconds: 300, Score: 0.2},
+	{MaxSeconds: -1, Score: 0.0}, // -1 MaxSeconds means "anything beyond the previous tier"
+}
+
+func delayScore(tiers []DelayTier, seconds int64) float64 {
+	for _, t := range tiers {
+		if t.MaxSeconds < 0 || seconds <= t.MaxSeconds {
+			return t.Score
+		}
+	}
+	return 0
+}
+
+// ElectionWeights controls how much each scoring dimension contributes
+// to a candidate's final score. All weights are relative to each
+// other, not normalized to any particular range.
+type ElectionWeights struct {
+	GTID     float64 `toml:"gtid"`
+	Delay    float64 `toml:"delay"`
+	SemiSync float64 `toml:"semisync"`
+	Compat   float64 `toml:"compat"`
+	Affinity float64 `toml:"affinity"`
+	Flapping float64 `toml:"flapping"`
+}
+
+// defaultElectionWeights mirrors the relative importance the previous
+// sum-of-GTID-seqnos election implicitly gave to replication
+// advancement, while giving the other dimensions enough weight to
+// actually matter.
+var defaultElectionWeights = ElectionWeights{
+	GTID:     10,
+	Delay:    5,
+	SemiSync: 3,
+	Compat:   3,
+	Affinity: 2,
+	Flapping: 8,
+}
+
+// ElectionConfig gathers everything electCandidate needs beyond the
+// package-level flags: per-dimension weights, datacenter/rack affinity
+// tags, and the flapping guard. All of it is expected to be loaded
+// from the config file.
+type ElectionConfig struct {
+	Weights          ElectionWeights
+	DelayTiers       []DelayTier
+	Affinity         map[string]string // server URL -> datacenter/rack tag
+	PreferredTag     string            // tag that matches prefMaster's locality
+	BlacklistWindow  time.Duration     // how long a recent promotion counts against a candidate
+	BlacklistMax     int               // promotions within BlacklistWindow before the penalty saturates
+	DryRunElection   bool              // log the scoreboard but never promote
+}
+
+// electionConfig is the active configuration; it holds the
+// package-level defaults until the config loader wires in real values.
+var electionConfig = ElectionConfig{
+	Weights:    defaultElectionWeights,
+	DelayTiers: defaultDelayTiers,
+}
+
+// recentPromotions tracks, per server URL, the timestamps of its last
+// few promotions to master so a server that keeps flapping in and out
+// of the master role can be penalized instead of re-elected every
+// time. recordPromotion (promote, on the failover/switchover path) and
+// flappingPenalty (electCandidate, reachable from the HTTP API
+// goroutine too) both touch it, so it's guarded by recentPromotionsMu.
+var (
+	recentPromotionsMu sync.Mutex
+	recentPromotions   = map[string][]time.Time{}
+)
+
+// recordPromotion must be called by the failover/switchover path right
+// after a candidate is actually promoted, so the next election can see
+// it.
+func recordPromotion(url string, at time.Time) {
+	recentPromotionsMu.Lock()
+	defer recentPromotionsMu.Unlock()
+	recentPromotions[url] = append(recentPromotions[url], at)
+}
+
+// flappingPenalty returns a score in [0,1] counting how many
+// promotions url has had within cfg.BlacklistWindow, saturating at 1
+// once it reaches cfg.BlacklistMax. It also prunes entries older than
+// cfg.BlacklistWindow while it's holding the lock, so recentPromotions
+// doesn't grow unbounded over the life of the process.
+func flappingPenalty(cfg ElectionConfig, url string, now time.Time) float64 {
+	if cfg.BlacklistMax <= 0 || cfg.BlacklistWindow <= 0 {
+		return 0
+	}
+	recentPromotionsMu.Lock()
+	defer recentPromotionsMu.Unlock()
+
+	kept := recentPromotions[url][:0]
+	count := 0
+	for _, t := range recentPromotions[url] {
+		if now.Sub(t) <= cfg.BlacklistWindow {
+			kept = append(kept, t)
+			count++
+		}
+	}
+	if len(kept) == 0 {
+		delete(recentPromotions, url)
+	} else {
+		recentPromotions[url] = kept
+	}
+
+	if count >= cfg.BlacklistMax {
+		return 1
+	}
+	return float64(count) / float64(cfg.BlacklistMax)
+}
+
+// CandidateScore is one row of the election scoreboard: either the
+// reason a candidate was hard-excluded, or its weighted score and the
+// per-dimension contributions that produced it.
+type CandidateScore struct {
+	Server        *ServerMonitor
+	Excluded      bool
+	ExcludeReason string
+	Score         float64
+	GTIDScore     float64 // normalized to [0,1] relative to the best candidate, not a raw seqno
+	DelayScore    float64
+	SemiSyncScore float64
+	CompatScore   float64
+	AffinityScore float64
+	FlapPenalty   float64
+}
+
+// ElectionResult replaces the previous single int return value:
+// callers need both the winner and the full scoreboard, whether to log
+// it, to drive --dry-run-election, or to expose it on the status
+// endpoint.
+type ElectionResult struct {
+	Winner      *ServerMonitor
+	WinnerIndex int
+	Scoreboard  []CandidateScore
+	Rigged      bool // true if the winner was forced by preferredCandidate
+	DryRun      bool // true if cfg.DryRunElection was set; callers must not promote Winner
+}
+
+// highestSeqnoPerDomain returns the max seqno observed for any single
+// GTID domain, rather than summing across domains: summing can make a
+// candidate that is slightly ahead in many stale domains outrank one
+// that is far ahead in the one domain that actually matters.
+func highestSeqnoPerDomain(l *gtid.List) uint64 {
+	var max uint64
+	for _, v := range l.GetSeqNos() {
+		if v > max {
+			max = v
+		}
+	}
+	return max
+}
+
+// electCandidate returns the candidate with the best weighted score
+// from a list of slaves. preferredCandidate, when non-empty, rigs the
+// election in favor of that server's URL (passed explicitly rather
+// than read off the prefMaster global, so concurrent callers - the
+// monitor loop and the HTTP API - can't stomp each other's choice).
+func (server *ServerMonitor) electCandidate(l []*ServerMonitor, preferredCandidate string) ElectionResult {
+	cfg := electionConfig
+	now := time.Now()
+	result := ElectionResult{WinnerIndex: -1, DryRun: cfg.DryRunElection}
+	metrics.ElectionsRun.Inc()
+
+	if verbose {
+		logger.Debugf("Processing %d candidates", len(l))
+	}
+
+	for i, sl := range l {
+		cs := CandidateScore{Server: sl}
+
+		if contains(ignoreList, sl.URL) {
+			cs.Excluded = true
+			cs.ExcludeReason = "in ignore list"
+			if verbose {
+				logger.Debugf("%s is in the ignore list. Skipping", sl.URL)
+			}
+			result.Scoreboard = append(result.Scoreboard, cs)
+			continue
+		}
+
+		// Refresh state before evaluating.
+		sl.refresh()
+
+		if server.State != stateFailed || server.State == stateMaster {
+			if verbose {
+				logger.Debugf("Checking eligibility of slave server %s [%d]", sl.URL, i)
+			}
+			if multiMaster && sl.State == stateMaster {
+				cs.Excluded = true
+				cs.ExcludeReason = "has state Master under multiMaster"
+				logger.Warnf("Slave %s has state Master. Skipping", sl.URL)
+				result.Scoreboard = append(result.Scoreboard, cs)
+				continue
+			}
+			if !dbhelper.CheckSlavePrerequisites(sl.Conn, sl.Host) {
+				cs.Excluded = true
+				cs.ExcludeReason = "fails slave prerequisites"
+				result.Scoreboard = append(result.Scoreboard, cs)
+				continue
+			}
+			if !dbhelper.CheckBinlogFilters(server.Conn, sl.Conn) {
+				cs.Excluded = true
+				cs.ExcludeReason = "binlog filters differ from master"
+				logger.Warnf("Binlog filters differ on master and slave %s. Skipping", sl.URL)
+				result.Scoreboard = append(result.Scoreboard, cs)
+				continue
+			}
+			if !dbhelper.CheckReplicationFilters(server.Conn, sl.Conn) {
+				cs.Excluded = true
+				cs.ExcludeReason = "replication filters differ from master"
+				logger.Warnf("Replication filters differ on master and slave %s. Skipping", sl.URL)
+				result.Scoreboard = append(result.Scoreboard, cs)
+				continue
+			}
+			ss, _ := dbhelper.GetSlaveStatus(sl.Conn)
+			if !ss.Seconds_Behind_Master.Valid {
+				cs.Excluded = true
+				cs.ExcludeReason = "replication stopped"
+				logger.Warnf("Slave %s is stopped. Skipping", sl.URL)
+				result.Scoreboard = append(result.Scoreboard, cs)
+				continue
+			}
+			if ss.Seconds_Behind_Master.Int64 > maxDelay {
+				cs.Excluded = true
+				cs.ExcludeReason = fmt.Sprintf("%ds behind master exceeds maxDelay %ds", ss.Seconds_Behind_Master.Int64, maxDelay)
+				logger.Warnf("Slave %s has more than %d seconds of replication delay (%d). Skipping", sl.URL, maxDelay, ss.Seconds_Behind_Master.Int64)
+				result.Scoreboard = append(result.Scoreboard, cs)
+				continue
+			}
+			if gtidCheck && !dbhelper.CheckSlaveSync(sl.Conn, server.Conn) {
+				cs.Excluded = true
+				cs.ExcludeReason = "not in sync with master"
+				logger.Warnf("Slave %s not in sync. Skipping", sl.URL)
+				result.Scoreboard = append(result.Scoreboard, cs)
+				continue
+			}
+			cs.DelayScore = delayScore(cfg.DelayTiers, ss.Seconds_Behind_Master.Int64)
+		} else {
+			cs.DelayScore = 1
+		}
+
+		/* Rig the election if the examined slave is the preferred candidate master */
+		if preferredCandidate != "" && sl.URL == preferredCandidate {
+			if verbose {
+				logger.Debugf("Election rig: %s elected as preferred master", sl.URL)
+			}
+			result.Winner = sl
+			result.WinnerIndex = i
+			result.Rigged = true
+			cs.Score = 1
+			result.Scoreboard = append(result.Scoreboard, cs)
+			return result
+		}
+
+		// GTIDScore holds the raw per-domain seqno for now; it is
+		// normalized to [0,1] relative to the best candidate once every
+		// candidate has been evaluated, below, so it weighs the same
+		// order of magnitude as the other 0-1 dimensions instead of
+		// dominating the sum.
+		cs.GTIDScore = float64(highestSeqnoPerDomain(sl.SlaveGtid))
+		if sl.SemiSyncMasterStatus {
+			cs.SemiSyncScore = 1
+		}
+		if sl.ReadOnly == "ON" && sl.LogBin == "ON" {
+			cs.CompatScore = 1
+		}
+		if cfg.PreferredTag != "" && cfg.Affinity[sl.URL] == cfg.PreferredTag {
+			cs.AffinityScore = 1
+		}
+		cs.FlapPenalty = flappingPenalty(cfg, sl.URL, now)
+
+		result.Scoreboard = append(result.Scoreboard, cs)
+	}
+
+	var maxGTID float64
+	for _, cs := range result.Scoreboard {
+		if !cs.Excluded && cs.GTIDScore > maxGTID {
+			maxGTID = cs.GTIDScore
+		}
+	}
+	for i, cs := range result.Scoreboard {
+		if cs.Excluded {
+			continue
+		}
+		if maxGTID > 0 {
+			cs.GTIDScore = cs.GTIDScore / maxGTID
+		}
+		cs.Score = cfg.Weights.GTID*cs.GTIDScore +
+			cfg.Weights.Delay*cs.DelayScore +
+			cfg.Weights.SemiSync*cs.SemiSyncScore +
+			cfg.Weights.Compat*cs.CompatScore +
+			cfg.Weights.Affinity*cs.AffinityScore -
+			cfg.Weights.Flapping*cs.FlapPenalty
+		result.Scoreboard[i] = cs
+	}
+
+	for _, cs := range result.Scoreboard {
+		if cs.Excluded {
+			metrics.CandidatesRejected.WithLabelValues(cs.ExcludeReason).Inc()
+		}
+	}
+
+	// Pick the highest score; ties are broken deterministically by
+	// ServerID (lowest wins) so repeated runs with identical inputs
+	// always pick the same winner, instead of depending on slice order.
+	best := -1
+	for i, cs := range result.Scoreboard {
+		if cs.Excluded || cs.Server == nil {
+			continue
+		}
+		if best == -1 {
+			best = i
+			continue
+		}
+		bs := result.Scoreboard[best]
+		if cs.Score > bs.Score || (cs.Score == bs.Score && cs.Server.ServerID < bs.Server.ServerID) {
+			best = i
+		}
+	}
+
+	if best == -1 {
+		logger.Errorf("No suitable candidates found.")
+		return result
+	}
+
+	result.Winner = result.Scoreboard[best].Server
+	result.WinnerIndex = best
+
+	if cfg.DryRunElection || verbose {
+		logScoreboard(result)
+	}
+
+	return result
+}
+
+// logScoreboard prints the ranked scoreboard without promoting
+// anything, which is what --dry-run-election relies on.
+func logScoreboard(result ElectionResult) {
+	ranked := make([]CandidateScore, len(result.Scoreboard))
+	copy(ranked, result.Scoreboard)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Score > ranked[j].Score
+	})
+	for _, cs := range ranked {
+		if cs.Server == nil {
+			continue
+		}
+		if cs.Excluded {
+			logger.Infof("election: %s excluded (%s)", cs.Server.URL, cs.ExcludeReason)
+			continue
+		}
+		logger.Infof("election: %s score=%.2f gtid=%.0f delay=%.2f semisync=%.0f compat=%.0f affinity=%.0f flap=-%.2f",
+			cs.Server.URL, cs.Score, cs.GTIDScore, cs.DelayScore, cs.SemiSyncScore, cs.CompatScore, cs.AffinityScore, cs.FlapPenalty)
+	}
+}
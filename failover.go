@@ -0,0 +1,111 @@
+// failover.go
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mariadb-corporation/replication-manager/metrics"
+	"github.com/tanji/mariadb-tools/dbhelper"
+)
+
+// slaveOf re-slaves server onto newMaster, the same CHANGE MASTER +
+// START SLAVE sequence rejoin() uses against the package-level
+// master, parameterized so failover can repoint every surviving slave
+// onto the newly promoted master.
+func (server *ServerMonitor) slaveOf(newMaster *ServerMonitor) error {
+	cm := "CHANGE MASTER TO master_host='" + newMaster.IP + "', master_port=" + newMaster.Port + ", master_user='" + rplUser + "', master_password='" + rplPass + "', MASTER_USE_GTID=CURRENT_POS"
+	if _, err := server.AdminConn.Exec(cm); err != nil {
+		return err
+	}
+	_, err := server.AdminConn.Exec("START SLAVE")
+	return err
+}
+
+// masterFailover promotes result.Winner in place of a master assumed
+// to be unreachable/failed: it freezes the old master (best effort, it
+// may already be unreachable), stops replication and lifts read-only
+// on the winner, re-slaves every other surviving slave onto the
+// winner, and repoints the package-level master/slaves globals. The
+// old master is dropped from the topology rather than re-slaved, since
+// it is presumed dead. The control API's POST /failover calls this;
+// the monitor's check() does not trigger a failover itself.
+//
+// masterSwitchover is the planned counterpart for a still-healthy
+// master: use that instead when the old master can be demoted and
+// kept around as a slave.
+func masterFailover(result ElectionResult) error {
+	return promote(result, false)
+}
+
+// masterSwitchover performs a planned rotation of a still-healthy
+// master: like masterFailover it promotes result.Winner, but the old
+// master is demoted (max_connections restored to its pre-freeze value,
+// read_only left on as freeze set it) and re-slaved onto the winner
+// instead of being abandoned, so POST /switchover leaves it as a
+// working slave instead of bricking it.
+func masterSwitchover(result ElectionResult) error {
+	return promote(result, true)
+}
+
+// promote is the shared body of masterFailover/masterSwitchover; see
+// their doc comments for what demoteOldMaster changes.
+func promote(result ElectionResult, demoteOldMaster bool) error {
+	if !isLeader() {
+		return fmt.Errorf("cluster: refusing to fail over, this instance is not the cluster leader")
+	}
+	if result.Winner == nil {
+		return fmt.Errorf("failover: no suitable candidate found")
+	}
+	if result.DryRun {
+		return fmt.Errorf("election: dry-run-election is set, not promoting %s", result.Winner.URL)
+	}
+	metrics.FailoversAttempted.Inc()
+
+	oldMaster := master
+	winner := result.Winner
+
+	if !oldMaster.freeze() {
+		logger.Warnf("Could not cleanly freeze old master %s before failover", oldMaster.URL)
+	}
+
+	if _, err := winner.AdminConn.Exec("STOP SLAVE"); err != nil {
+		return fmt.Errorf("failover: could not stop replication on candidate %s: %w", winner.URL, err)
+	}
+	if err := dbhelper.SetReadOnly(winner.AdminConn, false); err != nil {
+		return fmt.Errorf("failover: could not make %s writable: %w", winner.URL, err)
+	}
+
+	for _, s := range slaves {
+		if s.URL == winner.URL {
+			continue
+		}
+		if err := s.slaveOf(winner); err != nil {
+			logger.Errorf("Failed to re-slave %s onto new master %s: %s", s.URL, winner.URL, err)
+		}
+	}
+
+	winner.delete(&slaves)
+
+	if demoteOldMaster {
+		if maxConn != "" {
+			if _, err := oldMaster.AdminConn.Exec("SET GLOBAL max_connections=" + maxConn); err != nil {
+				logger.Errorf("Failed to restore max_connections on demoted master %s: %s", oldMaster.URL, err)
+			}
+		}
+		if err := oldMaster.slaveOf(winner); err != nil {
+			logger.Errorf("Failed to re-slave demoted master %s onto new master %s: %s", oldMaster.URL, winner.URL, err)
+		} else {
+			oldMaster.State = stateSlave
+			oldMaster.PrevState = stateSlave
+			slaves = append(slaves, oldMaster)
+		}
+	}
+
+	master = winner
+	master.State = stateMaster
+	master.PrevState = stateMaster
+	recordPromotion(winner.URL, time.Now())
+	metrics.FailoversSucceeded.Inc()
+	return nil
+}
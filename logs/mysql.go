@@ -0,0 +1,15 @@
+package logs
+
+// MySQLDriverLogger adapts a SubLogger to the go-sql-driver/mysql
+// Logger interface (Print(v ...interface{})), so driver-level errors
+// (connection resets, bad packets) get the same leveled/JSON treatment
+// as the rest of replication-manager's logs instead of going straight
+// to os.Stderr via the driver's own default logger.
+type MySQLDriverLogger struct {
+	*SubLogger
+}
+
+// Print implements github.com/go-sql-driver/mysql.Logger.
+func (m MySQLDriverLogger) Print(v ...interface{}) {
+	m.Errorf("%v", v)
+}
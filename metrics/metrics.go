@@ -0,0 +1,154 @@
+// Package metrics exposes replication-manager's internal state as
+// Prometheus gauges and counters, derived from the same data every
+// ServerMonitor.refresh() tick already computes, instead of leaving
+// operators to scrape /tmp/repmgr.state or grep logs.
+package metrics
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Per-server gauges, labeled by server URL so a single process
+// monitoring a whole topology exposes one series per server.
+var (
+	ReplicationDelaySeconds = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "replication_manager",
+		Name:      "replication_delay_seconds",
+		Help:      "Seconds_Behind_Master reported by the slave, -1 when unknown.",
+	}, []string{"server"})
+
+	SlaveIORunning = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "replication_manager",
+		Name:      "slave_io_running",
+		Help:      "1 if Slave_IO_Running is Yes, else 0.",
+	}, []string{"server"})
+
+	SlaveSQLRunning = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "replication_manager",
+		Name:      "slave_sql_running",
+		Help:      "1 if Slave_SQL_Running is Yes, else 0.",
+	}, []string{"server"})
+
+	SemiSyncMasterStatus = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "replication_manager",
+		Name:      "semi_sync_master_status",
+		Help:      "1 if RPL_SEMI_SYNC_MASTER_STATUS is ON, else 0.",
+	}, []string{"server"})
+
+	FailCount = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "replication_manager",
+		Name:      "fail_count",
+		Help:      "Consecutive failed health checks for the server.",
+	}, []string{"server"})
+
+	State = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "replication_manager",
+		Name:      "state",
+		Help:      "1 for the server's current state label, 0 for every other state.",
+	}, []string{"server", "state"})
+)
+
+// Manager-level counters, incremented from the election and failover
+// code paths rather than from refresh().
+var (
+	FailoversAttempted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "replication_manager",
+		Name:      "failovers_attempted_total",
+		Help:      "Number of failovers initiated by this instance.",
+	})
+
+	FailoversSucceeded = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "replication_manager",
+		Name:      "failovers_succeeded_total",
+		Help:      "Number of failovers that completed successfully.",
+	})
+
+	ElectionsRun = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "replication_manager",
+		Name:      "elections_run_total",
+		Help:      "Number of candidate elections run.",
+	})
+
+	CandidatesRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "replication_manager",
+		Name:      "candidates_rejected_total",
+		Help:      "Number of candidates excluded from an election, by reason.",
+	}, []string{"reason"})
+)
+
+// allKnownStates lists every value ServerMonitor.State can take so
+// State can be set to 0 for the states a server is NOT currently in,
+// rather than leaving stale series behind from a previous state.
+var allKnownStates = []string{"Master", "Slave", "Failed", "Unconnected", "Suspect"}
+
+func init() {
+	prometheus.MustRegister(
+		ReplicationDelaySeconds,
+		SlaveIORunning,
+		SlaveSQLRunning,
+		SemiSyncMasterStatus,
+		FailCount,
+		State,
+		FailoversAttempted,
+		FailoversSucceeded,
+		ElectionsRun,
+		CandidatesRejected,
+	)
+}
+
+// boolGauge converts a bool to a 0/1 gauge value. MySQL reports these
+// flags as "Yes"/"ON"-style text, so callers compare the text first.
+func boolGauge(yes bool) float64 {
+	if yes {
+		return 1
+	}
+	return 0
+}
+
+// ServerSample is the subset of ServerMonitor that Observe needs. It
+// is defined locally rather than importing package main (which would
+// create an import cycle) and kept deliberately narrow.
+type ServerSample struct {
+	URL                  string
+	State                string
+	FailCount            int
+	IOThreadRunning      bool
+	SQLThreadRunning     bool
+	SemiSyncMasterStatus bool
+	DelaySeconds         int64
+	DelayValid           bool
+}
+
+// Observe updates every per-server gauge from one refresh() tick.
+func Observe(s ServerSample) {
+	delay := -1.0
+	if s.DelayValid {
+		delay = float64(s.DelaySeconds)
+	}
+	ReplicationDelaySeconds.WithLabelValues(s.URL).Set(delay)
+	SlaveIORunning.WithLabelValues(s.URL).Set(boolGauge(s.IOThreadRunning))
+	SlaveSQLRunning.WithLabelValues(s.URL).Set(boolGauge(s.SQLThreadRunning))
+	SemiSyncMasterStatus.WithLabelValues(s.URL).Set(boolGauge(s.SemiSyncMasterStatus))
+	FailCount.WithLabelValues(s.URL).Set(float64(s.FailCount))
+	for _, st := range allKnownStates {
+		State.WithLabelValues(s.URL, st).Set(boolGauge(st == s.State))
+	}
+}
+
+// StartServer starts the Prometheus /metrics HTTP listener on addr
+// (e.g. ":9419") in the background. It does not block; the returned
+// error only reflects a failure to bind the listener.
+func StartServer(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	go http.Serve(ln, mux)
+	return nil
+}
@@ -2,6 +2,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"net/http"
@@ -10,16 +11,49 @@ import (
 	"sync"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	mysqldriver "github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
-	"github.com/mariadb-corporation/replication-manager/alert"
+	"github.com/mariadb-corporation/replication-manager/cluster"
+	"github.com/mariadb-corporation/replication-manager/dbconn"
 	"github.com/mariadb-corporation/replication-manager/gtid"
+	"github.com/mariadb-corporation/replication-manager/logs"
+	"github.com/mariadb-corporation/replication-manager/metrics"
 	"github.com/tanji/mariadb-tools/dbhelper"
 )
 
+// logger replaces the old logprint/logprintf helpers with a leveled,
+// optionally-JSON logger. It resolves logs.Default on every call, so a
+// Logger installed later with logs.SetDefault takes effect here too.
+var logger = logs.DefaultSub("monitor")
+
+func init() {
+	// Route the driver's own connection/protocol errors through the
+	// same logger instead of letting them go straight to stderr.
+	// DefaultSub (not Default.Sub) so this keeps working after
+	// SetDefault installs the CLI-configured logger post-flag-parse.
+	mysqldriver.SetLogger(logs.MySQLDriverLogger{SubLogger: logs.DefaultSub("mysql-driver")})
+}
+
+// ha is the cluster membership manager for this process. It is nil
+// when HA is not configured, in which case every instance behaves as
+// its own leader (the pre-HA single-process behavior).
+var ha *cluster.Manager
+
+// isLeader reports whether this instance is allowed to perform
+// mutating operations (failover, freeze, rejoin, writeState). With no
+// HA manager configured, a lone instance is always its own leader.
+func isLeader() bool {
+	if ha == nil {
+		return true
+	}
+	return ha.IsLeader()
+}
+
 // ServerMonitor defines a server to monitor.
 type ServerMonitor struct {
-	Conn                 *sqlx.DB
+	Conn                 *sqlx.DB // monitoring connection: Ping()/refresh(), small pool, never blocked by admin work
+	AdminConn            *sqlx.DB // admin connection: freeze/rejoin/SetReadOnly, kept separate so it can't starve health checks
+	pool                 *dbconn.Pool
 	URL                  string
 	Host                 string
 	Port                 string
@@ -46,6 +80,8 @@ type ServerMonitor struct {
 	FailCount            int
 	SemiSyncMasterStatus bool
 	RplMasterStatus      bool
+
+	nextPingAt time.Time // set after a failed tcp ping; check() skips pinging again until this passes
 }
 
 type serverList []*ServerMonitor
@@ -71,18 +107,22 @@ func newServerMonitor(url string) (*ServerMonitor, error) {
 		errmsg := fmt.Errorf("ERROR: DNS resolution error for host %s", server.Host)
 		return server, errmsg
 	}
-	params := fmt.Sprintf("?timeout=%ds", timeout)
-	mydsn := func() string {
-		dsn := dbUser + ":" + dbPass + "@"
-		if server.Host != "" {
-			dsn += "tcp(" + server.Host + ":" + server.Port + ")/" + params
-		} else {
-			dsn += "unix(" + socket + ")/" + params
-		}
-		return dsn
+	server.pool, err = dbconn.Open(dbconn.Config{
+		Host:           server.Host,
+		Port:           server.Port,
+		Sock:           socket,
+		User:           dbUser,
+		Pass:           dbPass,
+		ConnectTimeout: time.Duration(timeout) * time.Second,
+		TLSMode:        dbconn.TLSMode(dbTLSMode),
+		TLSConfigName:  dbTLSConfigName,
+	})
+	if err != nil {
+		return server, err
 	}
-	server.Conn, err = sqlx.Open("mysql", mydsn())
-	return server, err
+	server.Conn = server.pool.Monitor()
+	server.AdminConn = server.pool.Admin()
+	return server, nil
 }
 
 func (server *ServerMonitor) check(wg *sync.WaitGroup) {
@@ -96,7 +136,13 @@ func (server *ServerMonitor) check(wg *sync.WaitGroup) {
 	var err error
 	switch checktype {
 	case "tcp":
-		err = server.Conn.Ping()
+		if time.Now().Before(server.nextPingAt) {
+			return
+		}
+		err = server.pool.PingMonitor(context.Background())
+		if err != nil {
+			server.nextPingAt = time.Now().Add(server.pool.NextRetryDelay())
+		}
 	case "agent":
 		var resp *http.Response
 		resp, err = http.Get("http://" + server.Host + ":10001/check/")
@@ -106,17 +152,24 @@ func (server *ServerMonitor) check(wg *sync.WaitGroup) {
 		}
 	}
 
-	// Handle failure cases here
+	// Handle failure cases here. Followers keep refreshing their local
+	// view so they can take over instantly on election, but only the
+	// elected leader is allowed to act on a failure (bump FailCount,
+	// declare a master failed, trigger rejoin) to avoid a partitioned
+	// leader and a newly elected one both racing to fail over.
+	if err != nil && !isLeader() {
+		return
+	}
 	if err != nil {
 		if err != sql.ErrNoRows && (server.State == stateMaster || server.State == stateSuspect) {
 			server.FailCount++
 			if server.URL == master.URL {
 				if master.FailCount <= maxfail {
-					logprintf("WARN : Master Failure detected! Retry %d/%d", master.FailCount, maxfail)
+					logger.Warnf("Master Failure detected! Retry %d/%d", master.FailCount, maxfail)
 				}
 				if server.FailCount >= maxfail {
 					if server.FailCount == maxfail {
-						logprint("WARN : Declaring master as failed")
+						logger.Warnf("Declaring master as failed")
 					}
 					master.State = stateFailed
 				} else {
@@ -128,7 +181,7 @@ func (server *ServerMonitor) check(wg *sync.WaitGroup) {
 				server.FailCount++
 				if server.FailCount >= maxfail {
 					if server.FailCount == maxfail {
-						logprintf("WARN : Declaring server %s as failed", server.URL)
+						logger.Warnf("Declaring server %s as failed", server.URL)
 						server.State = stateFailed
 					} else {
 						server.State = stateSuspect
@@ -138,22 +191,14 @@ func (server *ServerMonitor) check(wg *sync.WaitGroup) {
 				}
 			}
 		}
-		// Send alert if state has changed
-		if server.PrevState != server.State && mailTo != "" {
+		// Send alert if state has changed, fanning out to every
+		// configured backend (SMTP, Slack, PagerDuty, syslog) instead
+		// of only ever emailing mailTo.
+		if server.PrevState != server.State {
 			if verbose {
-				logprintf("INFO : Server %s state changed from %s to %s", server.URL, server.PrevState, server.State)
-			}
-			a := alert.Alert{
-				From:        mailFrom,
-				To:          mailTo,
-				Type:        server.State,
-				Origin:      server.URL,
-				Destination: mailSMTPAddr,
-			}
-			err = a.Email()
-			if err != nil {
-				logprint("ERROR: Could not send email alert: ", err)
+				logger.Infof("Server %s state changed from %s to %s", server.URL, server.PrevState, server.State)
 			}
+			notifyTransition(server)
 		}
 		return
 	}
@@ -169,23 +214,23 @@ func (server *ServerMonitor) check(wg *sync.WaitGroup) {
 		// it as unconnected server.
 		if server.PrevState == stateFailed {
 			if loglevel > 1 {
-				logprintf("DEBUG: State comparison reinitialized failed server %s as unconnected", server.URL)
+				logger.Debugf("State comparison reinitialized failed server %s as unconnected", server.URL)
 			}
 			server.State = stateUnconn
 			server.FailCount = 0
 			if autorejoin {
 				// Check if master exists in topology before rejoining.
 				if server.URL != master.URL {
-					logprintf("INFO : Rejoining previously failed server %s", server.URL)
+					logger.Infof("Rejoining previously failed server %s", server.URL)
 					err = server.rejoin()
 					if err != nil {
-						logprintf("ERROR: Failed to autojoin previously failed server %s", server.URL)
+						logger.Errorf("Failed to autojoin previously failed server %s", server.URL)
 					}
 				}
 			}
 		} else if server.State != stateMaster {
 			if loglevel > 1 {
-				logprintf("DEBUG: State unconnected set by non-master rule on server %s", server.URL)
+				logger.Debugf("State unconnected set by non-master rule on server %s", server.URL)
 			}
 			server.State = stateUnconn
 		}
@@ -198,9 +243,9 @@ func (server *ServerMonitor) check(wg *sync.WaitGroup) {
 		server.FailCount = 0
 		slaves = append(slaves, server)
 		if readonly {
-			err = dbhelper.SetReadOnly(server.Conn, true)
+			err = dbhelper.SetReadOnly(server.AdminConn, true)
 			if err != nil {
-				logprintf("ERROR: Could not set rejoining slave %s as read-only, %s", server.URL, err)
+				logger.Errorf("Could not set rejoining slave %s as read-only, %s", server.URL, err)
 			}
 		}
 	}
@@ -208,6 +253,8 @@ func (server *ServerMonitor) check(wg *sync.WaitGroup) {
 
 /* Refresh a server object */
 func (server *ServerMonitor) refresh() error {
+	defer server.observeMetrics()
+
 	err := server.Conn.Ping()
 	if err != nil {
 		return err
@@ -264,6 +311,24 @@ func (server *ServerMonitor) refresh() error {
 	return nil
 }
 
+// observeMetrics pushes this server's current fields into the
+// Prometheus gauges. It runs on every refresh() tick, including the
+// partial-failure paths, so a server that just lost its slave status
+// still shows up with updated IO/SQL thread gauges instead of going
+// stale.
+func (server *ServerMonitor) observeMetrics() {
+	metrics.Observe(metrics.ServerSample{
+		URL:                  server.URL,
+		State:                server.State,
+		FailCount:            server.FailCount,
+		IOThreadRunning:      server.IOThread == "Yes",
+		SQLThreadRunning:     server.SQLThread == "Yes",
+		SemiSyncMasterStatus: server.SemiSyncMasterStatus,
+		DelaySeconds:         server.Delay.Int64,
+		DelayValid:           server.Delay.Valid,
+	})
+}
+
 /* Check replication health and return status string */
 func (server *ServerMonitor) healthCheck() string {
 	if server.State == stateMaster {
@@ -287,118 +352,66 @@ func (server *ServerMonitor) healthCheck() string {
 
 /* Handles write freeze and existing transactions on a server */
 func (server *ServerMonitor) freeze() bool {
-	err := dbhelper.SetReadOnly(server.Conn, true)
+	if !isLeader() {
+		logger.Warnf("Refusing to freeze %s, this instance is not the cluster leader", server.URL)
+		return false
+	}
+	err := dbhelper.SetReadOnly(server.AdminConn, true)
 	if err != nil {
-		logprintf("WARN : Could not set %s as read-only: %s", server.URL, err)
+		logger.Warnf("Could not set %s as read-only: %s", server.URL, err)
 		return false
 	}
 	for i := waitKill; i > 0; i -= 500 {
-		threads := dbhelper.CheckLongRunningWrites(server.Conn, 0)
+		threads := dbhelper.CheckLongRunningWrites(server.AdminConn, 0)
 		if threads == 0 {
 			break
 		}
-		logprintf("INFO : Waiting for %d write threads to complete on %s", threads, server.URL)
+		logger.Infof("Waiting for %d write threads to complete on %s", threads, server.URL)
 		time.Sleep(500 * time.Millisecond)
 	}
-	maxConn = dbhelper.GetVariableByName(server.Conn, "MAX_CONNECTIONS")
-	_, err = server.Conn.Exec("SET GLOBAL max_connections=0")
-	logprintf("INFO : Terminating all threads on %s", server.URL)
-	dbhelper.KillThreads(server.Conn)
+	maxConn = dbhelper.GetVariableByName(server.AdminConn, "MAX_CONNECTIONS")
+	_, err = server.AdminConn.Exec("SET GLOBAL max_connections=0")
+	logger.Infof("Terminating all threads on %s", server.URL)
+	dbhelper.KillThreads(server.AdminConn)
 	return true
 }
 
-/* Returns a candidate from a list of slaves. If there's only one slave it will be the de facto candidate. */
-func (server *ServerMonitor) electCandidate(l []*ServerMonitor) int {
-	ll := len(l)
-	if verbose {
-		logprintf("DEBUG: Processing %d candidates", ll)
-	}
-	seqList := make([]uint64, ll)
-	hiseq := 0
-	var max uint64
-	for i, sl := range l {
-		/* If server is in the ignore list, do not elect it */
-		if contains(ignoreList, sl.URL) {
-			if verbose {
-				logprintf("DEBUG: %s is in the ignore list. Skipping", sl.URL)
-			}
-			continue
-		}
-		// Refresh state before evaluating
-		sl.refresh()
-		if server.State != stateFailed || server.State == stateMaster {
-			if verbose {
-				logprintf("DEBUG: Checking eligibility of slave server %s [%d]", sl.URL, i)
-			}
-			if multiMaster == true && sl.State == stateMaster {
-				logprintf("WARN : Slave %s has state Master. Skipping", sl.URL)
-				continue
-			}
-			if dbhelper.CheckSlavePrerequisites(sl.Conn, sl.Host) == false {
-				continue
-			}
-			if dbhelper.CheckBinlogFilters(server.Conn, sl.Conn) == false {
-				logprintf("WARN : Binlog filters differ on master and slave %s. Skipping", sl.URL)
-				continue
-			}
-			if dbhelper.CheckReplicationFilters(server.Conn, sl.Conn) == false {
-				logprintf("WARN : Replication filters differ on master and slave %s. Skipping", sl.URL)
-				continue
-			}
-			ss, _ := dbhelper.GetSlaveStatus(sl.Conn)
-			if ss.Seconds_Behind_Master.Valid == false {
-				logprintf("WARN : Slave %s is stopped. Skipping", sl.URL)
-				continue
-			}
-			if ss.Seconds_Behind_Master.Int64 > maxDelay {
-				logprintf("WARN : Slave %s has more than %d seconds of replication delay (%d). Skipping", sl.URL, maxDelay, ss.Seconds_Behind_Master.Int64)
-				continue
-			}
-			if gtidCheck && dbhelper.CheckSlaveSync(sl.Conn, server.Conn) == false {
-				logprintf("WARN : Slave %s not in sync. Skipping", sl.URL)
-				continue
-			}
-		}
-		/* Rig the election if the examined slave is preferred candidate master */
-		if sl.URL == prefMaster {
-			if verbose {
-				logprintf("DEBUG: Election rig: %s elected as preferred master", sl.URL)
-			}
-			return i
-		}
-		seqnos := sl.SlaveGtid.GetSeqNos()
-		if verbose {
-			logprintf("DEBUG: Got sequence(s) %v for server [%d]", seqnos, i)
-		}
-		for _, v := range seqnos {
-			seqList[i] += v
-		}
-		if seqList[i] > max {
-			max = seqList[i]
-			hiseq = i
-		}
-	}
-	if max > 0 {
-		/* Return key of slave with the highest seqno. */
-		return hiseq
-	}
-	logprint("ERROR: No suitable candidates found.")
-	return -1
-}
+// electCandidate itself now lives in election.go: it returns a full
+// ElectionResult (winner plus ranked scoreboard) instead of a bare
+// index, so callers can log or expose the scoring that produced a
+// promotion decision.
 
 func (server *ServerMonitor) log() {
 	server.refresh()
-	logprintf("DEBUG: Server:%s Current GTID:%s Slave GTID:%s Binlog Pos:%s", server.URL, server.CurrentGtid.Sprint(), server.SlaveGtid.Sprint(), server.BinlogPos.Sprint())
+	logger.Debugf("Server:%s Current GTID:%s Slave GTID:%s Binlog Pos:%s", server.URL, server.CurrentGtid.Sprint(), server.SlaveGtid.Sprint(), server.BinlogPos.Sprint())
 	return
 }
 
 func (server *ServerMonitor) close() {
-	server.Conn.Close()
+	server.pool.Close()
 	return
 }
 
 func (server *ServerMonitor) writeState() error {
 	server.log()
+	if ha != nil {
+		// The snapshot lists the master and every current slave.
+		// LastCandidate and LastElectionAt are left empty.
+		snap := cluster.Snapshot{}
+		for _, s := range append([]*ServerMonitor{master}, slaves...) {
+			if s == nil {
+				continue
+			}
+			snap.Servers = append(snap.Servers, cluster.ServerState{
+				URL:       s.URL,
+				State:     s.State,
+				FailCount: s.FailCount,
+				BinlogPos: s.BinlogPos.Sprint(),
+			})
+		}
+		return ha.WriteState(context.Background(), snap)
+	}
+	// No HA configured: fall back to the legacy local state file.
 	f, err := os.Create("/tmp/repmgr.state")
 	if err != nil {
 		return err
@@ -433,11 +446,14 @@ func (server *ServerMonitor) delete(sl *serverList) {
 }
 
 func (server *ServerMonitor) rejoin() error {
+	if !isLeader() {
+		return fmt.Errorf("cluster: refusing to rejoin %s, this instance is not the cluster leader", server.URL)
+	}
 	if readonly {
-		dbhelper.SetReadOnly(server.Conn, true)
+		dbhelper.SetReadOnly(server.AdminConn, true)
 	}
 	cm := "CHANGE MASTER TO master_host='" + master.IP + "', master_port=" + master.Port + ", master_user='" + rplUser + "', master_password='" + rplPass + "', MASTER_USE_GTID=CURRENT_POS"
-	_, err := server.Conn.Exec(cm)
-	dbhelper.StartSlave(server.Conn)
+	_, err := server.AdminConn.Exec(cm)
+	dbhelper.StartSlave(server.AdminConn)
 	return err
 }